@@ -0,0 +1,50 @@
+// Package auth decouples coa-api from any single identity provider. An
+// Authenticator turns a bearer token into a Principal; which Authenticator
+// runs is chosen in main() from config, so the service can sit behind an
+// OIDC provider, accept static service-account tokens, or (for local
+// development) skip authentication altogether.
+package auth
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/go-accounting/coa-api/middleware"
+)
+
+// Principal is the authenticated identity behind a request, along with the
+// scopes it was granted and any claims the Authenticator chose to record
+// (OIDCAuthenticator records only the token's issuer; the others record none).
+type Principal struct {
+	Subject string
+	Scopes  []string
+	Claims  map[string]interface{}
+}
+
+// HasScope reports whether the principal was granted the given scope.
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator verifies a bearer token and resolves it to a Principal.
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*Principal, error)
+}
+
+// unauthorized logs the real reason an Authenticate call failed and wraps
+// it as a 401 with a single generic client-facing message. The real reason
+// (unknown issuer vs. bad signature vs. expired token vs. unknown service
+// account, ...) must never reach the caller: a 401 body that varies by
+// reason lets an attacker enumerate valid subjects and issuers by watching
+// which message comes back. This mirrors how middleware.Recover keeps a
+// panic's detail in the log and sends the client a fixed message.
+func unauthorized(logger *log.Logger, reason error) error {
+	logger.Printf("auth: %v", reason)
+	return middleware.NewHTTPError(http.StatusUnauthorized, "unauthorized", "invalid or expired token")
+}
@@ -0,0 +1,15 @@
+package auth
+
+import "context"
+
+// NoopAuthenticator grants the same fixed principal to every request. It
+// exists for local development against a service with no identity provider
+// configured and must never be selected outside of dev config.
+type NoopAuthenticator struct {
+	Subject string
+	Scopes  []string
+}
+
+func (a NoopAuthenticator) Authenticate(_ context.Context, _ string) (*Principal, error) {
+	return &Principal{Subject: a.Subject, Scopes: a.Scopes}, nil
+}
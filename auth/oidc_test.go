@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+)
+
+func fakeJWT(t *testing.T, claims interface{}) string {
+	t.Helper()
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("marshal claims: %v", err)
+	}
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	body := base64.RawURLEncoding.EncodeToString(payload)
+	return header + "." + body + ".sig"
+}
+
+func TestUnverifiedIssuer(t *testing.T) {
+	tok := fakeJWT(t, struct {
+		Issuer string `json:"iss"`
+	}{Issuer: "https://issuer.example.com"})
+
+	iss, err := unverifiedIssuer(tok)
+	if err != nil {
+		t.Fatalf("unverifiedIssuer returned error: %v", err)
+	}
+	if iss != "https://issuer.example.com" {
+		t.Fatalf("got issuer %q", iss)
+	}
+}
+
+func TestUnverifiedIssuer_Malformed(t *testing.T) {
+	for _, tok := range []string{"", "not-a-jwt", "a.b"} {
+		if _, err := unverifiedIssuer(tok); err == nil {
+			t.Errorf("unverifiedIssuer(%q): expected error, got nil", tok)
+		}
+	}
+}
+
+func TestContainsString(t *testing.T) {
+	list := []string{"a", "b", "c"}
+	if !containsString(list, "b") {
+		t.Errorf("expected list to contain %q", "b")
+	}
+	if containsString(list, "z") {
+		t.Errorf("expected list not to contain %q", "z")
+	}
+	if containsString(nil, "a") {
+		t.Errorf("expected nil list to contain nothing")
+	}
+}
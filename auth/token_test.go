@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-accounting/coa"
+	"github.com/go-accounting/coa-api/middleware"
+)
+
+func discardLogger() *log.Logger {
+	return log.New(io.Discard, "", 0)
+}
+
+type fakeStore map[string][]byte
+
+func (s fakeStore) Get(key string) ([]byte, error) {
+	raw, ok := s[key]
+	if !ok {
+		return nil, fmt.Errorf("fakeStore: no value for %q", key)
+	}
+	return raw, nil
+}
+
+var _ coa.KeyValueStore = fakeStore(nil)
+
+func signToken(subject, secret string, issuedAt time.Time) string {
+	ts := strconv.FormatInt(issuedAt.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(subject + "." + ts))
+	return subject + "." + ts + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newFakeStore(sa ServiceAccount) fakeStore {
+	raw, err := json.Marshal(sa)
+	if err != nil {
+		panic(err)
+	}
+	return fakeStore{"serviceaccount:" + sa.Subject: raw}
+}
+
+func TestTokenAuthenticator_Authenticate(t *testing.T) {
+	sa := ServiceAccount{Subject: "svc-billing", Secret: "s3cret", Scopes: []string{"entries:write"}}
+	store := newFakeStore(sa)
+	auth := NewTokenAuthenticator(store, discardLogger())
+
+	t.Run("valid token", func(t *testing.T) {
+		tok := signToken(sa.Subject, sa.Secret, time.Now())
+		p, err := auth.Authenticate(context.Background(), tok)
+		if err != nil {
+			t.Fatalf("Authenticate returned error: %v", err)
+		}
+		if p.Subject != sa.Subject || !p.HasScope("entries:write") {
+			t.Fatalf("unexpected principal: %+v", p)
+		}
+	})
+
+	t.Run("malformed token", func(t *testing.T) {
+		_, err := auth.Authenticate(context.Background(), "not-a-token")
+		assertUnauthorized(t, err)
+	})
+
+	t.Run("unknown service account", func(t *testing.T) {
+		tok := signToken("svc-ghost", "whatever", time.Now())
+		_, err := auth.Authenticate(context.Background(), tok)
+		assertUnauthorized(t, err)
+	})
+
+	t.Run("bad signature", func(t *testing.T) {
+		tok := signToken(sa.Subject, "wrong-secret", time.Now())
+		_, err := auth.Authenticate(context.Background(), tok)
+		assertUnauthorized(t, err)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		tok := signToken(sa.Subject, sa.Secret, time.Now().Add(-2*tokenTTL))
+		_, err := auth.Authenticate(context.Background(), tok)
+		assertUnauthorized(t, err)
+	})
+}
+
+func assertUnauthorized(t *testing.T, err error) {
+	t.Helper()
+	he, ok := err.(*middleware.HTTPError)
+	if !ok {
+		t.Fatalf("expected *middleware.HTTPError, got %T: %v", err, err)
+	}
+	if he.Status != 401 {
+		t.Fatalf("expected status 401, got %d", he.Status)
+	}
+}
+
+// TestTokenAuthenticator_FailureMessagesAreIndistinguishable guards against
+// a subject/issuer enumeration oracle: whatever the real reason a token was
+// rejected, the client-facing message must be the same generic string so a
+// caller can't tell "unknown service account" apart from "bad signature"
+// or "expired token" by reading the response body.
+func TestTokenAuthenticator_FailureMessagesAreIndistinguishable(t *testing.T) {
+	sa := ServiceAccount{Subject: "svc-billing", Secret: "s3cret"}
+	store := newFakeStore(sa)
+	auth := NewTokenAuthenticator(store, discardLogger())
+
+	cases := map[string]string{
+		"malformed token":          "not-a-token",
+		"unknown service account":  signToken("svc-ghost", "whatever", time.Now()),
+		"bad signature":            signToken(sa.Subject, "wrong-secret", time.Now()),
+		"expired token":            signToken(sa.Subject, sa.Secret, time.Now().Add(-2*tokenTTL)),
+	}
+
+	var message string
+	for name, tok := range cases {
+		_, err := auth.Authenticate(context.Background(), tok)
+		he, ok := err.(*middleware.HTTPError)
+		if !ok {
+			t.Fatalf("%s: expected *middleware.HTTPError, got %T: %v", name, err, err)
+		}
+		if message == "" {
+			message = he.Message
+		} else if he.Message != message {
+			t.Fatalf("%s: message %q differs from %q seen for another failure reason", name, he.Message, message)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-accounting/coa"
+	"github.com/go-accounting/coa-api/middleware"
+	"github.com/julienschmidt/httprouter"
+)
+
+// accountWithBalance decorates a coa.Account with its balance for the
+// "?balance=true" variant of getAccount, without requiring coa.Account
+// itself to carry a balance field.
+type accountWithBalance struct {
+	*coa.Account
+	Balance *coa.Balance `json:"balance"`
+}
+
+func entries(cr *repository, ps httprouter.Params, q url.Values, _ decoder) (interface{}, error) {
+	return cr.AllEntries(ps.ByName("coa"), filterFrom(q))
+}
+
+func saveEntry(cr *repository, ps httprouter.Params, _ url.Values, d decoder) (interface{}, error) {
+	e := &coa.Entry{}
+	if err := d(e); err != nil {
+		return nil, err
+	}
+	e.User = cr.user
+	return cr.SaveEntry(ps.ByName("coa"), e)
+}
+
+func accountEntries(cr *repository, ps httprouter.Params, q url.Values, _ decoder) (interface{}, error) {
+	return cr.AllAccountEntries(ps.ByName("coa"), ps.ByName("account"), filterFrom(q))
+}
+
+func balances(cr *repository, ps httprouter.Params, q url.Values, _ decoder) (interface{}, error) {
+	asOf, err := parseTime(q.Get("as_of"))
+	if err != nil {
+		return nil, err
+	}
+	from, err := parseTime(q.Get("from"))
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseTime(q.Get("to"))
+	if err != nil {
+		return nil, err
+	}
+	return cr.Balances(ps.ByName("coa"), from, to, asOf)
+}
+
+func parseTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, middleware.NewHTTPError(http.StatusBadRequest, "bad_request", err.Error())
+	}
+	return t, nil
+}
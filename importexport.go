@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/go-accounting/coa"
+	"github.com/go-accounting/coa-api/middleware"
+	"github.com/julienschmidt/httprouter"
+)
+
+// exportPageSize bounds how many accounts export pulls from CoaRepository
+// at a time, so a chart with tens of thousands of accounts streams to the
+// response writer instead of being buffered in memory.
+const exportPageSize = 500
+
+func importChart(cr *repository, w http.ResponseWriter, r *http.Request, ps httprouter.Params) error {
+	coaId := ps.ByName("coa")
+	ok, err := cr.HasRole(coaId, cr.user, coa.RoleEditor)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return middleware.NewHTTPError(http.StatusForbidden, "forbidden",
+			fmt.Sprintf("%s requires editor role on %s", cr.user, coaId))
+	}
+	accounts, err := decodeAccounts(r)
+	if err != nil {
+		return middleware.NewHTTPError(http.StatusBadRequest, "bad_request", err.Error())
+	}
+	if err := checkImportable(accounts); err != nil {
+		return middleware.NewHTTPError(http.StatusBadRequest, "bad_request", err.Error())
+	}
+	if err := cr.ImportAccounts(coaId, accounts); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func decodeAccounts(r *http.Request) ([]*coa.Account, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, fmt.Errorf("import: invalid Content-Type: %w", err)
+	}
+	switch mediaType {
+	case "application/json":
+		var accounts []*coa.Account
+		if err := json.NewDecoder(r.Body).Decode(&accounts); err != nil {
+			return nil, fmt.Errorf("import: %w", err)
+		}
+		return accounts, nil
+	case "text/csv":
+		return decodeAccountsCSV(r.Body)
+	case "application/xml", "text/xml":
+		return decodeAccountsXML(r.Body)
+	default:
+		return nil, fmt.Errorf("import: unsupported Content-Type %q", mediaType)
+	}
+}
+
+func decodeAccountsCSV(body io.Reader) ([]*coa.Account, error) {
+	reader := csv.NewReader(body)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("import: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[name] = i
+	}
+	for _, required := range []string{"code", "name", "parent", "type", "currency"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("import: missing column %q", required)
+		}
+	}
+	var accounts []*coa.Account
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("import: %w", err)
+		}
+		accounts = append(accounts, &coa.Account{
+			Code:     row[columns["code"]],
+			Name:     row[columns["name"]],
+			Parent:   row[columns["parent"]],
+			Type:     row[columns["type"]],
+			Currency: row[columns["currency"]],
+		})
+	}
+	return accounts, nil
+}
+
+// gnucashAccountNode is the OFX/GnuCash account-tree shape: a flat list of
+// <account> elements, parent linkage carried by the parent attribute rather
+// than nesting.
+type gnucashAccountNode struct {
+	XMLName  xml.Name `xml:"account"`
+	Code     string   `xml:"code,attr"`
+	Name     string   `xml:"name,attr"`
+	Parent   string   `xml:"parent,attr"`
+	Type     string   `xml:"type,attr"`
+	Currency string   `xml:"currency,attr"`
+}
+
+type gnucashAccountTree struct {
+	XMLName  xml.Name             `xml:"accounts"`
+	Accounts []gnucashAccountNode `xml:"account"`
+}
+
+func decodeAccountsXML(body io.Reader) ([]*coa.Account, error) {
+	var tree gnucashAccountTree
+	if err := xml.NewDecoder(body).Decode(&tree); err != nil {
+		return nil, fmt.Errorf("import: %w", err)
+	}
+	accounts := make([]*coa.Account, len(tree.Accounts))
+	for i, n := range tree.Accounts {
+		accounts[i] = &coa.Account{Code: n.Code, Name: n.Name, Parent: n.Parent, Type: n.Type, Currency: n.Currency}
+	}
+	return accounts, nil
+}
+
+// checkImportable rejects duplicate codes and cycles in the parent chain
+// before a single account is written, so import is all-or-nothing.
+func checkImportable(accounts []*coa.Account) error {
+	byCode := make(map[string]*coa.Account, len(accounts))
+	for _, a := range accounts {
+		if _, ok := byCode[a.Code]; ok {
+			return fmt.Errorf("import: duplicate account code %q", a.Code)
+		}
+		byCode[a.Code] = a
+	}
+	for _, a := range accounts {
+		seen := map[string]bool{a.Code: true}
+		for parent := a.Parent; parent != ""; {
+			if seen[parent] {
+				return fmt.Errorf("import: cycle in parent chain at account %q", a.Code)
+			}
+			seen[parent] = true
+			next, ok := byCode[parent]
+			if !ok {
+				break // parent isn't part of this import; coa validates it exists
+			}
+			parent = next.Parent
+		}
+	}
+	return nil
+}
+
+func exportChart(cr *repository, w http.ResponseWriter, r *http.Request, ps httprouter.Params) error {
+	coaId := ps.ByName("coa")
+	ok, err := cr.HasRole(coaId, cr.user, coa.RoleViewer)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return middleware.NewHTTPError(http.StatusForbidden, "forbidden",
+			fmt.Sprintf("%s requires viewer role on %s", cr.user, coaId))
+	}
+	accept := r.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, "text/csv"):
+		return exportAccountsCSV(w, cr, coaId)
+	case strings.Contains(accept, "xml"):
+		return exportAccountsXML(w, cr, coaId)
+	default:
+		return exportAccountsJSON(w, cr, coaId)
+	}
+}
+
+// eachAccount pages through a chart's accounts via the same CoaRepository
+// cursor used by the listing endpoint, so export never holds the whole
+// chart in memory at once.
+func eachAccount(cr *repository, coaId string, fn func(*coa.Account) error) error {
+	cursor := ""
+	for {
+		page, err := cr.AllAccounts(coaId, coa.Filter{Limit: exportPageSize, Cursor: cursor})
+		if err != nil {
+			return err
+		}
+		for _, a := range page.Items {
+			if err := fn(a); err != nil {
+				return err
+			}
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+func exportAccountsJSON(w http.ResponseWriter, cr *repository, coaId string) error {
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	first := true
+	err := eachAccount(cr, coaId, func(a *coa.Account) error {
+		if !first {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+		return json.NewEncoder(w).Encode(a)
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "]")
+	return err
+}
+
+func exportAccountsCSV(w http.ResponseWriter, cr *repository, coaId string) error {
+	w.Header().Set("Content-Type", "text/csv")
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"code", "name", "parent", "type", "currency"}); err != nil {
+		return err
+	}
+	err := eachAccount(cr, coaId, func(a *coa.Account) error {
+		return cw.Write([]string{a.Code, a.Name, a.Parent, a.Type, a.Currency})
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func exportAccountsXML(w http.ResponseWriter, cr *repository, coaId string) error {
+	w.Header().Set("Content-Type", "application/xml")
+	enc := xml.NewEncoder(w)
+	root := xml.StartElement{Name: xml.Name{Local: "accounts"}}
+	if err := enc.EncodeToken(root); err != nil {
+		return err
+	}
+	err := eachAccount(cr, coaId, func(a *coa.Account) error {
+		return enc.Encode(gnucashAccountNode{Code: a.Code, Name: a.Name, Parent: a.Parent, Type: a.Type, Currency: a.Currency})
+	})
+	if err != nil {
+		return err
+	}
+	if err := enc.EncodeToken(root.End()); err != nil {
+		return err
+	}
+	return enc.Flush()
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/go-accounting/coa"
+)
+
+func TestCheckImportable_DuplicateCode(t *testing.T) {
+	accounts := []*coa.Account{
+		{Code: "1000", Name: "Cash"},
+		{Code: "1000", Name: "Cash (again)"},
+	}
+	if err := checkImportable(accounts); err == nil {
+		t.Fatal("expected an error for a duplicate account code")
+	}
+}
+
+func TestCheckImportable_SelfCycle(t *testing.T) {
+	accounts := []*coa.Account{
+		{Code: "1000", Name: "Cash", Parent: "1000"},
+	}
+	if err := checkImportable(accounts); err == nil {
+		t.Fatal("expected an error for an account that is its own parent")
+	}
+}
+
+func TestCheckImportable_MultiNodeCycle(t *testing.T) {
+	accounts := []*coa.Account{
+		{Code: "1000", Name: "A", Parent: "1001"},
+		{Code: "1001", Name: "B", Parent: "1002"},
+		{Code: "1002", Name: "C", Parent: "1000"},
+	}
+	if err := checkImportable(accounts); err == nil {
+		t.Fatal("expected an error for a multi-node cycle in the parent chain")
+	}
+}
+
+func TestCheckImportable_ParentOutsideBatchIsNotACycle(t *testing.T) {
+	// "assets" isn't part of this import batch, so checkImportable has no
+	// way to follow its chain further; that's coa's job to validate once
+	// the accounts are written, not import's.
+	accounts := []*coa.Account{
+		{Code: "1000", Name: "Cash", Parent: "assets"},
+	}
+	if err := checkImportable(accounts); err != nil {
+		t.Fatalf("unexpected error for a parent outside the batch: %v", err)
+	}
+}
+
+func TestCheckImportable_NoCycle(t *testing.T) {
+	accounts := []*coa.Account{
+		{Code: "1000", Name: "Assets"},
+		{Code: "1001", Name: "Cash", Parent: "1000"},
+		{Code: "1002", Name: "Checking", Parent: "1001"},
+	}
+	if err := checkImportable(accounts); err != nil {
+		t.Fatalf("unexpected error for a valid chain: %v", err)
+	}
+}
@@ -6,21 +6,25 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
 	"path"
 	"strings"
 	"sync"
+	"syscall"
+	"time"
 
-	oidc "github.com/coreos/go-oidc"
 	"github.com/go-accounting/coa"
+	"github.com/go-accounting/coa-api/auth"
+	"github.com/go-accounting/coa-api/middleware"
 	"github.com/go-accounting/config"
 	"github.com/julienschmidt/httprouter"
 )
 
 var cfg config.Config
 
-var provider *oidc.Provider
-var verifier *oidc.IDTokenVerifier
+var authenticator auth.Authenticator
 
 type repository struct {
 	*coa.CoaRepository
@@ -42,18 +46,28 @@ var repositoryPool = sync.Pool{
 type decoder func(interface{}) error
 
 func handler(
-	f func(*repository, httprouter.Params, decoder) (interface{}, error),
-) func(http.ResponseWriter, *http.Request, httprouter.Params) {
-	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		user, err := user(r)
+	requiredScope string,
+	f func(*repository, httprouter.Params, url.Values, decoder) (interface{}, error),
+) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ps := httprouter.ParamsFromContext(r.Context())
+		p, err := authenticate(r)
 		if check(err, w) {
 			return
 		}
+		middleware.InfoFromContext(r.Context()).User = p.Subject
+		if !p.HasScope(requiredScope) {
+			check(forbidden(requiredScope), w)
+			return
+		}
 		cr := repositoryPool.Get().(*repository)
-		cr.user = user
+		cr.user = p.Subject
 		defer repositoryPool.Put(cr)
-		v, err := f(cr, ps, func(v interface{}) error {
-			return json.NewDecoder(r.Body).Decode(v)
+		v, err := f(cr, ps, r.URL.Query(), func(v interface{}) error {
+			if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+				return middleware.NewHTTPError(http.StatusBadRequest, "bad_request", err.Error())
+			}
+			return nil
 		})
 		if check(err, w) {
 			return
@@ -62,18 +76,22 @@ func handler(
 			w.Header().Set("Content-Type", "application/json")
 			check(json.NewEncoder(w).Encode(v), w)
 		}
-	}
+	})
+}
+
+func forbidden(requiredScope string) error {
+	return middleware.NewHTTPError(http.StatusForbidden, "forbidden", fmt.Sprintf("missing required scope %q", requiredScope))
 }
 
-func chartsOfAccounts(cr *repository, _ httprouter.Params, _ decoder) (interface{}, error) {
-	return cr.AllChartsOfAccounts()
+func chartsOfAccounts(cr *repository, _ httprouter.Params, q url.Values, _ decoder) (interface{}, error) {
+	return cr.AllChartsOfAccounts(filterFrom(q))
 }
 
-func getChartOfAccounts(cr *repository, ps httprouter.Params, _ decoder) (interface{}, error) {
+func getChartOfAccounts(cr *repository, ps httprouter.Params, _ url.Values, _ decoder) (interface{}, error) {
 	return cr.GetChartOfAccounts(ps.ByName("coa"))
 }
 
-func saveChartsOfAccounts(cr *repository, ps httprouter.Params, d decoder) (interface{}, error) {
+func saveChartsOfAccounts(cr *repository, ps httprouter.Params, _ url.Values, d decoder) (interface{}, error) {
 	c := &coa.ChartOfAccounts{}
 	if err := d(c); err != nil {
 		return nil, err
@@ -85,15 +103,23 @@ func saveChartsOfAccounts(cr *repository, ps httprouter.Params, d decoder) (inte
 	return cr.SaveChartOfAccounts(c)
 }
 
-func accounts(cr *repository, ps httprouter.Params, _ decoder) (interface{}, error) {
-	return cr.AllAccounts(ps.ByName("coa"))
+func accounts(cr *repository, ps httprouter.Params, q url.Values, _ decoder) (interface{}, error) {
+	return cr.AllAccounts(ps.ByName("coa"), filterFrom(q))
 }
 
-func getAccount(cr *repository, ps httprouter.Params, _ decoder) (interface{}, error) {
-	return cr.GetAccount(ps.ByName("coa"), ps.ByName("account"))
+func getAccount(cr *repository, ps httprouter.Params, q url.Values, _ decoder) (interface{}, error) {
+	a, err := cr.GetAccount(ps.ByName("coa"), ps.ByName("account"))
+	if err != nil || a == nil || q.Get("balance") != "true" {
+		return a, err
+	}
+	b, err := cr.Balances(ps.ByName("coa"), time.Time{}, time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	return &accountWithBalance{Account: a, Balance: b[a.Id]}, nil
 }
 
-func saveAccount(cr *repository, ps httprouter.Params, d decoder) (interface{}, error) {
+func saveAccount(cr *repository, ps httprouter.Params, _ url.Values, d decoder) (interface{}, error) {
 	a := &coa.Account{}
 	if err := d(a); err != nil {
 		return nil, err
@@ -107,33 +133,89 @@ func saveAccount(cr *repository, ps httprouter.Params, d decoder) (interface{},
 
 func check(err error, w http.ResponseWriter) bool {
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		middleware.WriteError(w, err)
 	}
 	return err != nil
 }
 
-func user(r *http.Request) (string, error) {
+// rawHandler is the raw-http counterpart to handler, for endpoints that
+// negotiate their own content type and write the response body themselves
+// (import/export) instead of returning a value to be JSON-encoded.
+func rawHandler(
+	requiredScope string,
+	f func(*repository, http.ResponseWriter, *http.Request, httprouter.Params) error,
+) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ps := httprouter.ParamsFromContext(r.Context())
+		p, err := authenticate(r)
+		if check(err, w) {
+			return
+		}
+		middleware.InfoFromContext(r.Context()).User = p.Subject
+		if !p.HasScope(requiredScope) {
+			check(forbidden(requiredScope), w)
+			return
+		}
+		cr := repositoryPool.Get().(*repository)
+		cr.user = p.Subject
+		defer repositoryPool.Put(cr)
+		check(f(cr, w, r, ps), w)
+	})
+}
+
+func authenticate(r *http.Request) (*auth.Principal, error) {
 	var token string
 	tokens, ok := r.Header["Authorization"]
 	if ok && len(tokens) >= 1 {
-		token = tokens[0]
-		token = strings.TrimPrefix(token, "Bearer ")
-	}
-	idtoken, err := verifier.Verify(r.Context(), token)
-	if err != nil {
-		return "", err
-	}
-	var claims struct {
-		Email    string `json:"email"`
-		Verified bool   `json:"email_verified"`
+		token = strings.TrimPrefix(tokens[0], "Bearer ")
 	}
-	if err := idtoken.Claims(&claims); err != nil {
-		return "", err
+	return authenticator.Authenticate(r.Context(), token)
+}
+
+// newAuthenticator selects and builds the Authenticator to run from
+// cfg["Auth/Type"] ("oidc", the default; "token"; or "noop" for local dev).
+func newAuthenticator(logger *log.Logger) (auth.Authenticator, error) {
+	switch cfg["Auth/Type"] {
+	case "token":
+		v, err := cfg.Run("NewKeyValueStore", new(string))
+		if err != nil {
+			return nil, err
+		}
+		return auth.NewTokenAuthenticator(v.(coa.KeyValueStore), logger), nil
+	case "noop":
+		return auth.NoopAuthenticator{
+			Subject: cfg["Auth/Noop/Subject"].(string),
+			Scopes:  []string{"coa:read", "coa:write", "entry:post"},
+		}, nil
+	default:
+		return auth.NewOIDCAuthenticator(context.Background(), issuerConfigs(), logger)
 	}
-	if !claims.Verified {
-		return "", fmt.Errorf("email not verified")
+}
+
+// issuerConfigs reads cfg["OpenId/Issuers"], a list of {Provider, ClientId,
+// Audience} maps, falling back to the single-issuer cfg["OpenId/Provider"]
+// / cfg["OpenId/ClientId"] keys this service used before it supported more
+// than one IdP.
+func issuerConfigs() []auth.IssuerConfig {
+	if raw, ok := cfg["OpenId/Issuers"].([]interface{}); ok {
+		issuers := make([]auth.IssuerConfig, 0, len(raw))
+		for _, item := range raw {
+			m := item.(map[string]interface{})
+			ic := auth.IssuerConfig{
+				Issuer:   m["Provider"].(string),
+				ClientID: m["ClientId"].(string),
+			}
+			if a, ok := m["Audience"].(string); ok {
+				ic.Audience = a
+			}
+			issuers = append(issuers, ic)
+		}
+		return issuers
 	}
-	return claims.Email, nil
+	return []auth.IssuerConfig{{
+		Issuer:   cfg["OpenId/Provider"].(string),
+		ClientID: cfg["OpenId/ClientId"].(string),
+	}}
 }
 
 func main() {
@@ -146,19 +228,59 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	provider, err = oidc.NewProvider(context.Background(), cfg["OpenId/Provider"].(string))
+	logger := log.New(os.Stdout, "", 0)
+	authenticator, err = newAuthenticator(logger)
 	if err != nil {
 		log.Fatal(err)
 	}
-	verifier = provider.Verifier(&oidc.Config{ClientID: cfg["OpenId/ClientId"].(string)})
 	router := httprouter.New()
-	router.GET("/charts-of-accounts", handler(chartsOfAccounts))
-	router.POST("/charts-of-accounts", handler(saveChartsOfAccounts))
-	router.GET("/charts-of-accounts/:coa", handler(getChartOfAccounts))
-	router.PUT("/charts-of-accounts/:coa", handler(saveChartsOfAccounts))
-	router.GET("/charts-of-accounts/:coa/accounts", handler(accounts))
-	router.POST("/charts-of-accounts/:coa/accounts", handler(saveAccount))
-	router.GET("/charts-of-accounts/:coa/accounts/:account", handler(getAccount))
-	router.PUT("/charts-of-accounts/:coa/accounts/:account", handler(saveAccount))
-	log.Fatal(http.ListenAndServe(":8080", router))
+	route(router, "GET", "/charts-of-accounts", handler("coa:read", chartsOfAccounts))
+	route(router, "POST", "/charts-of-accounts", handler("coa:write", saveChartsOfAccounts))
+	route(router, "GET", "/charts-of-accounts/:coa", handler("coa:read", withRole(coa.RoleViewer, getChartOfAccounts)))
+	route(router, "PUT", "/charts-of-accounts/:coa", handler("coa:write", withRole(coa.RoleEditor, saveChartsOfAccounts)))
+	route(router, "GET", "/charts-of-accounts/:coa/accounts", handler("coa:read", withRole(coa.RoleViewer, accounts)))
+	route(router, "POST", "/charts-of-accounts/:coa/accounts", handler("coa:write", withRole(coa.RoleEditor, saveAccount)))
+	route(router, "GET", "/charts-of-accounts/:coa/accounts/:account", handler("coa:read", withRole(coa.RoleViewer, getAccount)))
+	route(router, "PUT", "/charts-of-accounts/:coa/accounts/:account", handler("coa:write", withRole(coa.RoleEditor, saveAccount)))
+	route(router, "GET", "/charts-of-accounts/:coa/entries", handler("coa:read", withRole(coa.RoleViewer, entries)))
+	route(router, "POST", "/charts-of-accounts/:coa/entries", handler("entry:post", withRole(coa.RolePoster, saveEntry)))
+	route(router, "GET", "/charts-of-accounts/:coa/accounts/:account/entries", handler("coa:read", withRole(coa.RoleViewer, accountEntries)))
+	route(router, "GET", "/charts-of-accounts/:coa/balances", handler("coa:read", withRole(coa.RoleViewer, balances)))
+	route(router, "GET", "/charts-of-accounts/:coa/members", handler("coa:read", withRole(coa.RoleViewer, members)))
+	route(router, "POST", "/charts-of-accounts/:coa/members", handler("coa:write", withRole(coa.RoleOwner, saveMember)))
+	route(router, "DELETE", "/charts-of-accounts/:coa/members/:user", handler("coa:write", withRole(coa.RoleOwner, deleteMember)))
+	route(router, "POST", "/charts-of-accounts/:coa/import", rawHandler("coa:write", importChart))
+	route(router, "GET", "/charts-of-accounts/:coa/export", rawHandler("coa:read", exportChart))
+	router.Handler("GET", "/metrics", middleware.Handler())
+
+	h := middleware.Chain(router, middleware.RequestID, middleware.AccessLog(logger), middleware.Recover(logger))
+	srv := &http.Server{
+		Addr:              ":8080",
+		Handler:           h,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      30 * time.Second,
+	}
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	<-stop
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// route registers h for method and pattern and wraps it with a latency
+// histogram labeled by the path template itself and the shared, unlabeled
+// in-flight gauge.
+func route(router *httprouter.Router, method, pattern string, h http.Handler) {
+	router.Handler(method, pattern, middleware.Instrument(pattern, h))
 }
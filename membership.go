@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/go-accounting/coa"
+	"github.com/go-accounting/coa-api/middleware"
+	"github.com/julienschmidt/httprouter"
+)
+
+// withRole wraps a handler so it only runs once the caller's membership on
+// the :coa in the path meets the minimum role. Routes with no :coa segment
+// yet (creating a chart of accounts, listing the caller's own charts) are
+// left untouched, since there is no membership to check.
+func withRole(
+	role coa.Role,
+	f func(*repository, httprouter.Params, url.Values, decoder) (interface{}, error),
+) func(*repository, httprouter.Params, url.Values, decoder) (interface{}, error) {
+	return func(cr *repository, ps httprouter.Params, q url.Values, d decoder) (interface{}, error) {
+		coaId := ps.ByName("coa")
+		if coaId == "" {
+			return f(cr, ps, q, d)
+		}
+		ok, err := cr.HasRole(coaId, cr.user, role)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, middleware.NewHTTPError(http.StatusForbidden, "forbidden",
+				fmt.Sprintf("%s requires %s role on %s", cr.user, role, coaId))
+		}
+		return f(cr, ps, q, d)
+	}
+}
+
+func members(cr *repository, ps httprouter.Params, _ url.Values, _ decoder) (interface{}, error) {
+	return cr.AllMemberships(ps.ByName("coa"))
+}
+
+func saveMember(cr *repository, ps httprouter.Params, _ url.Values, d decoder) (interface{}, error) {
+	m := &coa.Membership{}
+	if err := d(m); err != nil {
+		return nil, err
+	}
+	m.CoaId = ps.ByName("coa")
+	return cr.SaveMembership(m)
+}
+
+func deleteMember(cr *repository, ps httprouter.Params, _ url.Values, _ decoder) (interface{}, error) {
+	return nil, cr.DeleteMembership(ps.ByName("coa"), ps.ByName("user"))
+}
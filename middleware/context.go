@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// HeaderRequestID is the header a request ID is read from and echoed back
+// on, so a caller (or an upstream proxy) can supply its own.
+const HeaderRequestID = "X-Request-Id"
+
+type contextKey int
+
+const infoKey contextKey = iota
+
+// RequestInfo is installed into the request context once, before routing,
+// by RequestID. Downstream code fills it in further as the request is
+// processed (main.go sets User once a handler has authenticated the
+// caller), and AccessLog reads the final state after the handler returns.
+type RequestInfo struct {
+	RequestID string
+	User      string
+}
+
+// InfoFromContext returns the RequestInfo installed by RequestID, or a
+// zero-value one if RequestID never ran.
+func InfoFromContext(ctx context.Context) *RequestInfo {
+	if info, ok := ctx.Value(infoKey).(*RequestInfo); ok {
+		return info
+	}
+	return &RequestInfo{}
+}
+
+// RequestID assigns every request an ID, reusing the caller's X-Request-Id
+// if it sent one, and stores it alongside a slot for the eventual
+// authenticated user in the request context.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(HeaderRequestID)
+		if id == "" {
+			id = newRequestID()
+		}
+		w.Header().Set(HeaderRequestID, id)
+		ctx := context.WithValue(r.Context(), infoKey, &RequestInfo{RequestID: id})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func newRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b[:])
+}
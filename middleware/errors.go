@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// HTTPError is the typed error model handlers return in place of a bare
+// error, so WriteError can render the status the caller actually earned
+// (400, 403, ...) instead of the one-size-fits-all 500 the service used to
+// send for everything.
+type HTTPError struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func NewHTTPError(status int, code, message string) *HTTPError {
+	return &HTTPError{Status: status, Code: code, Message: message}
+}
+
+func (e *HTTPError) Error() string {
+	return e.Message
+}
+
+// WriteError renders err as a JSON error body. Errors that aren't an
+// *HTTPError are treated as unexpected failures and reported as 500.
+func WriteError(w http.ResponseWriter, err error) {
+	he, ok := err.(*HTTPError)
+	if !ok {
+		he = NewHTTPError(http.StatusInternalServerError, "internal", err.Error())
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(he.Status)
+	json.NewEncoder(w).Encode(he)
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteError_HTTPError(t *testing.T) {
+	w := httptest.NewRecorder()
+	WriteError(w, NewHTTPError(http.StatusForbidden, "forbidden", "nope"))
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	var body struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if body.Code != "forbidden" || body.Message != "nope" {
+		t.Fatalf("unexpected body: %+v", body)
+	}
+}
+
+func TestWriteError_PlainError(t *testing.T) {
+	w := httptest.NewRecorder()
+	WriteError(w, errors.New("boom"))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if body.Code != "internal" {
+		t.Fatalf("code = %q, want %q", body.Code, "internal")
+	}
+}
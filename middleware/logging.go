@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// AccessLog emits one structured JSON line per request: method, path,
+// status, latency, the authenticated user (if any), and the request ID
+// RequestID assigned, so a request can be traced end to end.
+func AccessLog(logger *log.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			info := InfoFromContext(r.Context())
+			entry, _ := json.Marshal(map[string]interface{}{
+				"method":      r.Method,
+				"path":        r.URL.Path,
+				"status":      rec.status,
+				"duration_ms": time.Since(start).Milliseconds(),
+				"user":        info.User,
+				"request_id":  info.RequestID,
+			})
+			logger.Println(string(entry))
+		})
+	}
+}
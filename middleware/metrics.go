@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "coa_api",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of coa-api HTTP requests by route and status.",
+	}, []string{"method", "route", "status"})
+
+	requestsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "coa_api",
+		Name:      "requests_in_flight",
+		Help:      "Number of coa-api HTTP requests currently being served.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(requestDuration, requestsInFlight)
+}
+
+// Instrument wraps a single route's handler with a latency histogram and
+// an in-flight gauge. route must be the registered path template (e.g.
+// "/charts-of-accounts/:coa"), not the matched URL, so the method+route+
+// status label stays bounded regardless of how many distinct :coa or
+// :account values get requested.
+func Instrument(route string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestsInFlight.Inc()
+		defer requestsInFlight.Dec()
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		requestDuration.WithLabelValues(r.Method, route, strconv.Itoa(rec.status)).Observe(time.Since(start).Seconds())
+	})
+}
+
+// Handler exposes the registered metrics for scraping.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
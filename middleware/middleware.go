@@ -0,0 +1,20 @@
+// Package middleware replaces the ad-hoc error handling and bare
+// http.ListenAndServe that used to live in main.go with a small,
+// composable chain of func(http.Handler) http.Handler: request-ID
+// propagation, structured access logs, panic recovery, and a typed error
+// model that renders as JSON instead of a raw 500.
+package middleware
+
+import "net/http"
+
+// Middleware is one link in a request-processing chain.
+type Middleware func(http.Handler) http.Handler
+
+// Chain wraps h with mws, applied outermost first: Chain(h, A, B) serves
+// requests as A(B(h)).
+func Chain(h http.Handler, mws ...Middleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestChain_OrderAndPassthrough(t *testing.T) {
+	var order []string
+
+	tag := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+	}), tag("A"), tag("B"))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	want := []string{"A", "B", "handler"}
+	if len(order) != len(want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order = %v, want %v", order, want)
+		}
+	}
+}
+
+func TestChain_NoMiddleware(t *testing.T) {
+	called := false
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if !called {
+		t.Fatal("handler was not called")
+	}
+}
+
+// TestChain_RecoverInnermostStillLogsAccess pins down the order main.go
+// wires these three in: Recover must be innermost so a panic still passes
+// through AccessLog and gets a status/duration logged, instead of
+// unwinding straight past it.
+func TestChain_RecoverInnermostStillLogsAccess(t *testing.T) {
+	var logBuf bytes.Buffer
+	logger := log.New(&logBuf, "", 0)
+
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}), RequestID, AccessLog(logger), Recover(logger))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	logged := logBuf.String()
+	if !strings.Contains(logged, `"status":500`) {
+		t.Fatalf("expected AccessLog to record the panic's 500 status, got: %s", logged)
+	}
+	if !strings.Contains(logged, "panic: request_id=") {
+		t.Fatalf("expected Recover to log the panic, got: %s", logged)
+	}
+}
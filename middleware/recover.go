@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"runtime/debug"
+)
+
+// Recover turns a panic anywhere downstream into a 500 HTTPError instead of
+// crashing the connection. The panic value and stack trace are logged for
+// diagnosis but never reach the client — they can easily contain internal
+// detail (a file path, a query, an object the caller shouldn't see).
+//
+// Recover must wrap the router directly (innermost in the chain), below
+// RequestID and AccessLog: a panic unwinds straight past any middleware
+// that Recover wraps, so anything meant to observe the request — the access log,
+// the request ID — has to already be in place by the time Recover's
+// deferred recover() runs.
+func Recover(logger *log.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				if rec := recover(); rec != nil {
+					requestID := InfoFromContext(r.Context()).RequestID
+					logger.Printf("panic: request_id=%s %v\n%s", requestID, rec, debug.Stack())
+					WriteError(w, NewHTTPError(http.StatusInternalServerError, "internal", "internal server error"))
+				}
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
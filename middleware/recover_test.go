@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecover_HidesPanicValueFromResponse(t *testing.T) {
+	var logBuf bytes.Buffer
+	logger := log.New(&logBuf, "", 0)
+
+	h := RequestID(Recover(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("super secret internal detail")
+	})))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(HeaderRequestID, "req-123")
+	h.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	if strings.Contains(w.Body.String(), "super secret internal detail") {
+		t.Fatalf("response body leaked the panic value: %s", w.Body.String())
+	}
+	if !strings.Contains(logBuf.String(), "super secret internal detail") {
+		t.Fatalf("expected panic value to be logged, got: %s", logBuf.String())
+	}
+	if !strings.Contains(logBuf.String(), "req-123") {
+		t.Fatalf("expected panic log to carry the request ID for correlation, got: %s", logBuf.String())
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/go-accounting/coa"
+)
+
+// filterFrom turns the "?limit=&cursor=&q=&parent=&type=" query parameters
+// shared by every listing endpoint into a coa.Filter. cursor is the opaque
+// value coa.Page.NextCursor handed back on the previous page; coa rejects it
+// if any of the other filters changed since it was issued.
+func filterFrom(q url.Values) coa.Filter {
+	f := coa.Filter{
+		Cursor: q.Get("cursor"),
+		Q:      q.Get("q"),
+		Parent: q.Get("parent"),
+		Type:   q.Get("type"),
+	}
+	if limit, err := strconv.Atoi(q.Get("limit")); err == nil && limit > 0 {
+		f.Limit = limit
+	}
+	return f
+}
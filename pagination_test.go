@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestFilterFrom(t *testing.T) {
+	cases := []struct {
+		name      string
+		query     url.Values
+		wantLimit int
+	}{
+		{"missing limit", url.Values{}, 0},
+		{"zero limit", url.Values{"limit": {"0"}}, 0},
+		{"negative limit", url.Values{"limit": {"-5"}}, 0},
+		{"non-numeric limit", url.Values{"limit": {"abc"}}, 0},
+		{"valid limit", url.Values{"limit": {"20"}}, 20},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f := filterFrom(c.query)
+			if f.Limit != c.wantLimit {
+				t.Errorf("Limit = %d, want %d", f.Limit, c.wantLimit)
+			}
+		})
+	}
+}
+
+func TestFilterFrom_PassesThroughOtherFields(t *testing.T) {
+	q := url.Values{
+		"cursor": {"abc123"},
+		"q":      {"cash"},
+		"parent": {"assets"},
+		"type":   {"asset"},
+	}
+	f := filterFrom(q)
+	if f.Cursor != "abc123" || f.Q != "cash" || f.Parent != "assets" || f.Type != "asset" {
+		t.Errorf("unexpected filter: %+v", f)
+	}
+}